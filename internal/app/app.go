@@ -1,12 +1,17 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	grpcapp "sso/internal/app/grpc"
 	"sso/internal/config"
+	jwtlocal "sso/internal/lib"
+	"sso/internal/lib/email"
 	"sso/internal/services/auth"
+	"sso/internal/services/selfcheck"
 	"sso/internal/storage/postgresql"
+	"sso/schema"
 	// sqlite "sso/internal/storage/sqllite"
 	//"time"
 )
@@ -22,7 +27,30 @@ func New(log *slog.Logger, cfg *config.Config) *App { // TTL - time to live
 		panic(err)
 	}
 
-	auth := auth.NewAuth(log, storage, storage, storage, storage, cfg.GRPC.Timeout)
+	schemaVersion, err := schema.Version()
+	if err != nil {
+		panic(err)
+	}
+
+	// отчет только логируется: в proto контракте нет GetServerInfo
+	selfcheck.New(log, storage, cfg, schemaVersion).Run(context.Background())
+
+	emailValid := email.NewValidator(cfg.Email.CheckMX, cfg.Email.MXTimeout, cfg.Email.MXCacheTTL)
+
+	limits := jwtlocal.ClaimsLimits{
+		MaxTokenSize:    cfg.Token.MaxSize,
+		MaxCustomClaims: cfg.Token.MaxCustomClaims,
+		MaxClaimsDepth:  cfg.Token.MaxClaimsDepth,
+		ReservedClaims:  cfg.Token.ReservedClaims,
+	}
+
+	// ошибка в token.claims сломала бы каждый логин, поэтому падаем на старте
+	if err := jwtlocal.CheckCustomClaims(cfg.Token.Claims, limits); err != nil {
+		panic(err)
+	}
+
+	auth := auth.NewAuth(log, storage, storage, storage, storage, storage, emailValid, cfg.GRPC.Timeout,
+		limits, cfg.Token.Claims)
 
 	grpcApp := grpcapp.New(log, cfg.GRPC.Port, auth)
 
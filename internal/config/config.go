@@ -9,11 +9,14 @@ import (
 )
 
 type Config struct {
-	Env         string        `yaml:"env" env-default:"local"`
-	StoragePath string        `yaml:"storage_path"`
-	TokenTTL    time.Duration `yaml:"token_ttl" env-required:"true"`
-	GRPC        GRPCConfig    `yaml:"grpc"`
-	DB          DBConfig      `yaml:"db"`
+	Env         string          `yaml:"env" env-default:"local"`
+	StoragePath string          `yaml:"storage_path"`
+	TokenTTL    time.Duration   `yaml:"token_ttl" env-required:"true"`
+	GRPC        GRPCConfig      `yaml:"grpc"`
+	DB          DBConfig        `yaml:"db"`
+	Email       EmailConfig     `yaml:"email"`
+	Token       TokenConfig     `yaml:"token"`
+	SelfCheck   SelfCheckConfig `yaml:"self_check"`
 }
 
 type DBConfig struct {
@@ -26,6 +29,28 @@ type DBConfig struct {
 	Timeout  time.Duration `mapstructure:"timeout"`
 }
 
+type EmailConfig struct {
+	CheckMX    bool          `yaml:"check_mx" env-default:"false"`
+	MXTimeout  time.Duration `yaml:"mx_timeout" env-default:"2s"`
+	MXCacheTTL time.Duration `yaml:"mx_cache_ttl" env-default:"1h"`
+}
+
+type TokenConfig struct {
+	MaxSize         int      `yaml:"max_size" env-default:"8192"`
+	MaxCustomClaims int      `yaml:"max_custom_claims" env-default:"32"`
+	MaxClaimsDepth  int      `yaml:"max_claims_depth" env-default:"4"`
+	ReservedClaims  []string `yaml:"reserved_claims"`
+	// Claims are static custom claims added to every token, e.g. an issuer-specific tenant
+	Claims map[string]interface{} `yaml:"claims"`
+}
+
+type SelfCheckConfig struct {
+	NTPServer     string        `yaml:"ntp_server" env-default:"pool.ntp.org:123"`
+	NTPTimeout    time.Duration `yaml:"ntp_timeout" env-default:"1s"`
+	MaxClockDrift time.Duration `yaml:"max_clock_drift" env-default:"2s"`
+	Timeout       time.Duration `yaml:"timeout" env-default:"5s"`
+}
+
 type GRPCConfig struct {
 	Port    int           `yaml:"port"`
 	Timeout time.Duration `yaml:"timeout"`
@@ -1,7 +1,10 @@
 package models
 
+import "time"
+
 type App struct {
 	Id int
 	Name string
 	Secret []byte
+	RevokedBefore time.Time
 }
\ No newline at end of file
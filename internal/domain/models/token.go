@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type TokenClaims struct {
+	UserID    int64
+	Email     string
+	AppID     int
+	RolesVer  int64
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// RevokeFilter selects tokens for bulk revocation, AppID 0 means all apps
+// and zero IssuedBefore means all tokens issued up to now
+type RevokeFilter struct {
+	AppID        int64
+	IssuedBefore time.Time
+}
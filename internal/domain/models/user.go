@@ -4,4 +4,5 @@ type User struct {
 	ID int64
 	Email string
 	PassHash []byte
+	RolesVer int64
 }
\ No newline at end of file
@@ -83,6 +83,12 @@ func (s *serverAPI) Register(ctx context.Context, req *ssov1.RegisterRequest) (*
 		if errors.Is(err, auth.ErrUserExists) {
 			return nil, status.Error(codes.AlreadyExists, fmt.Sprintf("User already exist with email: %s", req.GetEmail()))
 		}
+		if errors.Is(err, auth.ErrInvalidEmail) {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("Invalid email: %s", req.GetEmail()))
+		}
+		if errors.Is(err, auth.ErrUndeliverableEmail) {
+			return nil, status.Error(codes.FailedPrecondition, fmt.Sprintf("Email is undeliverable: %s", req.GetEmail()))
+		}
 		return nil, status.Error(codes.Internal, "Iternal error: "+err.Error())
 	}
 	return &ssov1.RegisterResponse{UserId: userId}, nil
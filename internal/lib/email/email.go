@@ -0,0 +1,181 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/idna"
+)
+
+var (
+	ErrInvalidEmail       = errors.New("invalid email")
+	ErrUndeliverableEmail = errors.New("email domain has no mx records")
+)
+
+// maxCacheSize bounds the mx cache, expired entries are evicted when it is full
+const maxCacheSize = 10000
+
+// Resolver is the subset of net.Resolver used for deliverability checks
+type Resolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+type mxEntry struct {
+	ok        bool
+	expiresAt time.Time
+}
+
+// Validator normalizes emails and optionally checks that their domain accepts mail
+type Validator struct {
+	checkMX  bool
+	timeout  time.Duration
+	cacheTTL time.Duration
+	resolver Resolver
+
+	mu    sync.Mutex
+	cache map[string]mxEntry
+}
+
+// NewValidator returns a new object of the Validator struct
+func NewValidator(checkMX bool, timeout time.Duration, cacheTTL time.Duration) *Validator {
+	return &Validator{
+		checkMX:  checkMX,
+		timeout:  timeout,
+		cacheTTL: cacheTTL,
+		resolver: net.DefaultResolver,
+		cache:    make(map[string]mxEntry),
+	}
+}
+
+// Normalize lowercases the domain part and converts IDN domains to punycode
+func Normalize(email string) (string, error) {
+	const op = "email.Normalize"
+
+	email = strings.TrimSuffix(strings.TrimSpace(email), ".")
+
+	// адрес должен быть голым addr-spec, без имени и угловых скобок
+	parsed, err := mail.ParseAddress(email)
+	if err != nil || parsed.Address != email {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidEmail)
+	}
+
+	at := strings.LastIndex(email, "@")
+	local, domain := email[:at], email[at+1:]
+
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil || !strings.Contains(ascii, ".") {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidEmail)
+	}
+
+	return local + "@" + strings.ToLower(ascii), nil
+}
+
+// Validate returns the normalized email or an error if it is malformed or undeliverable
+func (v *Validator) Validate(ctx context.Context, email string) (string, error) {
+	const op = "email.Validate"
+
+	normalized, err := Normalize(email)
+	if err != nil {
+		return "", err
+	}
+
+	if !v.checkMX {
+		return normalized, nil
+	}
+
+	domain := normalized[strings.LastIndex(normalized, "@")+1:]
+
+	ok, err := v.hasMX(ctx, domain)
+	if err != nil {
+		// dns недоступен - не блокируем регистрацию
+		return normalized, nil
+	}
+	if !ok {
+		return "", fmt.Errorf("%s: %w", op, ErrUndeliverableEmail)
+	}
+
+	return normalized, nil
+}
+
+func (v *Validator) hasMX(ctx context.Context, domain string) (bool, error) {
+	v.mu.Lock()
+	entry, found := v.cache[domain]
+	v.mu.Unlock()
+
+	if found && time.Now().Before(entry.expiresAt) {
+		return entry.ok, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, v.timeout)
+	defer cancel()
+
+	ok, err := v.lookup(ctx, domain)
+	if err != nil {
+		return false, err
+	}
+
+	v.store(domain, ok)
+
+	return ok, nil
+}
+
+// lookup reports the domain as undeliverable only on NXDOMAIN or null mx (RFC 7505)
+func (v *Validator) lookup(ctx context.Context, domain string) (bool, error) {
+	records, err := v.resolver.LookupMX(ctx, domain)
+	if err != nil && !isNotFound(err) {
+		return false, err
+	}
+
+	for _, mx := range records {
+		if mx.Host == "" || mx.Host == "." {
+			return false, nil
+		}
+	}
+	if len(records) > 0 {
+		return true, nil
+	}
+
+	// без mx почта идет на A/AAAA запись домена (RFC 5321 5.1)
+	hosts, err := v.resolver.LookupHost(ctx, domain)
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return len(hosts) > 0, nil
+}
+
+func (v *Validator) store(domain string, ok bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := time.Now()
+
+	if len(v.cache) >= maxCacheSize {
+		for key, entry := range v.cache {
+			if !now.Before(entry.expiresAt) {
+				delete(v.cache, key)
+			}
+		}
+	}
+	if len(v.cache) >= maxCacheSize {
+		// кэш забит живыми записями - начинаем заново
+		v.cache = make(map[string]mxEntry)
+	}
+
+	v.cache[domain] = mxEntry{ok: ok, expiresAt: now.Add(v.cacheTTL)}
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
@@ -0,0 +1,166 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubResolver struct {
+	mx        map[string][]*net.MX
+	hosts     map[string][]string
+	err       error
+	mxCalls   int
+	hostCalls int
+}
+
+func (r *stubResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	r.mxCalls++
+	if r.err != nil {
+		return nil, r.err
+	}
+	if records, ok := r.mx[name]; ok {
+		return records, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r *stubResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	r.hostCalls++
+	if hosts, ok := r.hosts[host]; ok {
+		return hosts, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func newTestValidator(resolver Resolver) *Validator {
+	v := NewValidator(true, time.Second, time.Hour)
+	v.resolver = resolver
+	return v
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		email   string
+		want    string
+		wantErr bool
+	}{
+		{name: "plain", email: "bob@example.com", want: "bob@example.com"},
+		{name: "uppercase domain", email: "Bob@Example.COM", want: "Bob@example.com"},
+		{name: "trailing dot", email: "bob@example.com.", want: "bob@example.com"},
+		{name: "spaces around", email: "  bob@example.com ", want: "bob@example.com"},
+		{name: "idn", email: "bob@пример.рф", want: "bob@xn--e1afmkfd.xn--p1ai"},
+		{name: "idn uppercase", email: "bob@ПРИМЕР.РФ", want: "bob@xn--e1afmkfd.xn--p1ai"},
+		{name: "empty", email: "", wantErr: true},
+		{name: "no at", email: "bob.example.com", wantErr: true},
+		{name: "empty local", email: "@example.com", wantErr: true},
+		{name: "empty domain", email: "bob@", wantErr: true},
+		{name: "two at", email: "a@b@example.com", wantErr: true},
+		{name: "space in local", email: "a b@example.com", wantErr: true},
+		{name: "control char", email: "a\x01b@example.com", wantErr: true},
+		{name: "display name", email: "Bob <bob@example.com>", wantErr: true},
+		{name: "single label domain", email: "bob@localhost", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.email)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidEmail)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	resolver := &stubResolver{
+		mx: map[string][]*net.MX{
+			"mail.com": {{Host: "mx.mail.com.", Pref: 10}},
+			"null.com": {{Host: ".", Pref: 0}},
+			"nomx.com": {},
+		},
+		hosts: map[string][]string{
+			"nomx.com": {"192.0.2.1"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		email   string
+		wantErr error
+	}{
+		{name: "mx", email: "bob@mail.com"},
+		{name: "implicit mx", email: "bob@nomx.com"},
+		{name: "null mx", email: "bob@null.com", wantErr: ErrUndeliverableEmail},
+		{name: "nxdomain", email: "bob@missing.com", wantErr: ErrUndeliverableEmail},
+		{name: "malformed", email: "bob", wantErr: ErrInvalidEmail},
+	}
+
+	v := newTestValidator(resolver)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.Validate(context.Background(), tt.email)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestValidate_ResolverError(t *testing.T) {
+	v := newTestValidator(&stubResolver{err: errors.New("connection refused")})
+
+	got, err := v.Validate(context.Background(), "bob@Example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "bob@example.com", got)
+	assert.Empty(t, v.cache)
+}
+
+func TestValidate_CacheHit(t *testing.T) {
+	resolver := &stubResolver{mx: map[string][]*net.MX{"null.com": {{Host: "."}}}}
+	v := newTestValidator(resolver)
+
+	for i := 0; i < 3; i++ {
+		_, err := v.Validate(context.Background(), "bob@null.com")
+		require.ErrorIs(t, err, ErrUndeliverableEmail)
+	}
+
+	assert.Equal(t, 1, resolver.mxCalls)
+}
+
+func TestValidate_CheckDisabled(t *testing.T) {
+	resolver := &stubResolver{}
+	v := NewValidator(false, time.Second, time.Hour)
+	v.resolver = resolver
+
+	_, err := v.Validate(context.Background(), "bob@missing.com")
+	require.NoError(t, err)
+	assert.Zero(t, resolver.mxCalls)
+}
+
+func TestStore_EvictsExpired(t *testing.T) {
+	v := newTestValidator(&stubResolver{})
+
+	expired := time.Now().Add(-time.Minute)
+	for i := 0; i < maxCacheSize; i++ {
+		v.cache[fmt.Sprintf("d%d.com", i)] = mxEntry{ok: true, expiresAt: expired}
+	}
+
+	v.store("fresh.com", true)
+
+	assert.Len(t, v.cache, 1)
+	assert.True(t, v.cache["fresh.com"].ok)
+}
@@ -1,25 +1,212 @@
 package jwtlocal
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
 	"sso/internal/domain/models"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func NewToken(user models.User, app models.App, duration time.Duration) (string, error) {
+var (
+	ErrInvalidToken  = errors.New("invalid token")
+	ErrReservedClaim = errors.New("claim name is reserved")
+	ErrTooManyClaims = errors.New("too many custom claims")
+	ErrClaimsTooDeep = errors.New("custom claim is nested too deep")
+	ErrTokenTooLarge = errors.New("token is too large")
+)
+
+// MinSecretLen is the minimal HS256 key size, RFC 7518 3.2 requires at least 256 bits
+const MinSecretLen = 32
+
+// reservedClaims are set by NewToken itself and registered JWT claims, custom claims can not override them
+var reservedClaims = []string{"uid", "email", "exp", "app_id", "roles_ver", "iss", "sub", "aud", "nbf", "iat", "jti"}
+
+// ClaimsLimits restricts custom claims and the final token, zero value disables the limit
+type ClaimsLimits struct {
+	MaxTokenSize    int
+	MaxCustomClaims int
+	MaxClaimsDepth  int
+	ReservedClaims  []string
+}
+
+func NewToken(user models.User, app models.App, duration time.Duration,
+	custom map[string]interface{}, limits ClaimsLimits) (string, error) {
+	const op = "jwtlocal.NewToken"
+
+	if err := CheckCustomClaims(custom, limits); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
 	token := jwt.New(jwt.SigningMethodHS256)
 
+	now := time.Now()
+
 	claims := token.Claims.(jwt.MapClaims)
+	for name, value := range custom {
+		claims[name] = value
+	}
 	claims["uid"] = user.ID
 	claims["email"] = user.Email
-	claims["exp"] = time.Now().Add(duration).Unix()
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(duration).Unix()
 	claims["app_id"] = app.Id
+	claims["roles_ver"] = user.RolesVer
 
 	tokenString, err := token.SignedString([]byte(app.Secret))
 	if err != nil {
 		return "", err
 	}
 
+	if limits.MaxTokenSize > 0 && len(tokenString) > limits.MaxTokenSize {
+		return "", fmt.Errorf("%s: %w: %d bytes, max %d", op, ErrTokenTooLarge, len(tokenString), limits.MaxTokenSize)
+	}
+
 	return tokenString, nil
 }
+
+// CheckCustomClaims validates custom claims against the limits without minting a token
+func CheckCustomClaims(custom map[string]interface{}, limits ClaimsLimits) error {
+	if limits.MaxCustomClaims > 0 && len(custom) > limits.MaxCustomClaims {
+		return fmt.Errorf("%w: %d, max %d", ErrTooManyClaims, len(custom), limits.MaxCustomClaims)
+	}
+
+	for name, value := range custom {
+		if isReserved(name, limits.ReservedClaims) {
+			return fmt.Errorf("%w: %s", ErrReservedClaim, name)
+		}
+		if limits.MaxClaimsDepth > 0 && claimDepth(value, limits.MaxClaimsDepth) > limits.MaxClaimsDepth {
+			return fmt.Errorf("%w: %s, max depth %d", ErrClaimsTooDeep, name, limits.MaxClaimsDepth)
+		}
+	}
+
+	return nil
+}
+
+func isReserved(name string, extra []string) bool {
+	for _, reserved := range reservedClaims {
+		if name == reserved {
+			return true
+		}
+	}
+	for _, reserved := range extra {
+		if name == reserved {
+			return true
+		}
+	}
+
+	return false
+}
+
+// claimDepth returns 1 for scalar values and grows by one for every nested map, slice, array or struct.
+// The walk stops as soon as the depth exceeds limit, so cyclic values can not recurse forever
+func claimDepth(value interface{}, limit int) int {
+	return valueDepth(reflect.ValueOf(value), limit)
+}
+
+func valueDepth(v reflect.Value, limit int) int {
+	for i := 0; v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface; i++ {
+		if v.IsNil() {
+			return 1
+		}
+		// указатель, ссылающийся сам на себя
+		if i > limit {
+			return limit + 1
+		}
+		v = v.Elem()
+	}
+
+	var children []reflect.Value
+
+	switch v.Kind() {
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			children = append(children, iter.Value())
+		}
+	case reflect.Slice, reflect.Array:
+		// []byte кодируется в json строкой
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return 1
+		}
+		for i := 0; i < v.Len(); i++ {
+			children = append(children, v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				children = append(children, v.Field(i))
+			}
+		}
+	default:
+		return 1
+	}
+
+	if len(children) == 0 {
+		return 1
+	}
+	// непустой контейнер глубже 1, дальше не спускаемся
+	if limit < 2 {
+		return 2
+	}
+
+	depth := 0
+	for _, child := range children {
+		depth = max(depth, valueDepth(child, limit-1))
+		if depth+1 > limit {
+			break
+		}
+	}
+
+	return depth + 1
+}
+
+// ParseToken verifies the token with the secret of the app it was issued for
+func ParseToken(tokenString string, secret func(appID int64) ([]byte, error)) (models.TokenClaims, error) {
+	const op = "jwtlocal.ParseToken"
+
+	var res models.TokenClaims
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+		appID, ok := claims["app_id"].(float64)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+		return secret(int64(appID))
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return res, fmt.Errorf("%s: %w: %s", op, ErrInvalidToken, err.Error())
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+
+	uid, okUID := claims["uid"].(float64)
+	appID, okApp := claims["app_id"].(float64)
+	exp, okExp := claims["exp"].(float64)
+	if !okUID || !okApp || !okExp {
+		return res, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	res.UserID = int64(uid)
+	res.AppID = int(appID)
+	res.ExpiresAt = time.Unix(int64(exp), 0)
+	res.Email, _ = claims["email"].(string)
+	// без iat токен считается выпущенным в начале эпохи и отзывается любым фильтром
+	if iat, ok := claims["iat"].(float64); ok {
+		res.IssuedAt = time.Unix(int64(iat), 0)
+	} else {
+		res.IssuedAt = time.Unix(0, 0)
+	}
+	// токены выпущенные до появления roles_ver считаются версией 0
+	if rolesVer, ok := claims["roles_ver"].(float64); ok {
+		res.RolesVer = int64(rolesVer)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,237 @@
+package jwtlocal
+
+import (
+	"errors"
+	"sso/internal/domain/models"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func testSecretFunc(appID int64) ([]byte, error) {
+	if appID != 1 {
+		return nil, errors.New("app not found")
+	}
+	return testSecret, nil
+}
+
+func signClaims(t *testing.T, method jwt.SigningMethod, claims jwt.MapClaims, key interface{}) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	require.NoError(t, err)
+
+	return token
+}
+
+func TestParseToken_HappyPath(t *testing.T) {
+	user := models.User{ID: 7, Email: "bob@example.com", RolesVer: 3}
+	app := models.App{Id: 1, Secret: testSecret}
+
+	token, err := NewToken(user, app, time.Hour, nil, ClaimsLimits{})
+	require.NoError(t, err)
+
+	claims, err := ParseToken(token, testSecretFunc)
+	require.NoError(t, err)
+
+	assert.Equal(t, user.ID, claims.UserID)
+	assert.Equal(t, user.Email, claims.Email)
+	assert.Equal(t, app.Id, claims.AppID)
+	assert.Equal(t, user.RolesVer, claims.RolesVer)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), claims.ExpiresAt, time.Second)
+}
+
+func TestParseToken_LegacyWithoutRolesVer(t *testing.T) {
+	token := signClaims(t, jwt.SigningMethodHS256, jwt.MapClaims{
+		"uid":    7,
+		"email":  "bob@example.com",
+		"app_id": 1,
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}, testSecret)
+
+	claims, err := ParseToken(token, testSecretFunc)
+	require.NoError(t, err)
+	assert.Zero(t, claims.RolesVer)
+}
+
+func TestParseToken_Invalid(t *testing.T) {
+	valid := jwt.MapClaims{
+		"uid":    7,
+		"app_id": 1,
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}
+
+	expired := jwt.MapClaims{
+		"uid":    7,
+		"app_id": 1,
+		"exp":    time.Now().Add(-time.Minute).Unix(),
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "expired", token: signClaims(t, jwt.SigningMethodHS256, expired, testSecret)},
+		{name: "wrong alg", token: signClaims(t, jwt.SigningMethodHS512, valid, testSecret)},
+		{name: "alg none", token: signClaims(t, jwt.SigningMethodNone, valid, jwt.UnsafeAllowNoneSignatureType)},
+		{name: "wrong secret", token: signClaims(t, jwt.SigningMethodHS256, valid, []byte("other-secret"))},
+		{name: "unknown app", token: signClaims(t, jwt.SigningMethodHS256, jwt.MapClaims{
+			"uid": 7, "app_id": 2, "exp": time.Now().Add(time.Hour).Unix(),
+		}, testSecret)},
+		{name: "without uid", token: signClaims(t, jwt.SigningMethodHS256, jwt.MapClaims{
+			"app_id": 1, "exp": time.Now().Add(time.Hour).Unix(),
+		}, testSecret)},
+		{name: "garbage", token: "not.a.token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseToken(tt.token, testSecretFunc)
+			require.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
+
+func TestClaimDepth(t *testing.T) {
+	type inner struct {
+		Tags []string
+	}
+	type outer struct {
+		Inner inner
+		note  map[string]map[string][]int
+	}
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  int
+	}{
+		{name: "scalar", value: "admin", want: 1},
+		{name: "nil", value: nil, want: 1},
+		{name: "bytes", value: []byte("raw"), want: 1},
+		{name: "empty map", value: map[string]interface{}{}, want: 1},
+		{name: "generic map", value: map[string]interface{}{"a": 1}, want: 2},
+		{name: "typed map", value: map[string]string{"a": "b"}, want: 2},
+		{name: "typed slice", value: []string{"a"}, want: 2},
+		{name: "nested typed slice", value: [][]int{{1}}, want: 3},
+		{name: "array", value: [2]int{1, 2}, want: 2},
+		{name: "nested typed map", value: map[string]map[string][]int{"a": {"b": {1}}}, want: 4},
+		{name: "struct skips unexported", value: outer{Inner: inner{Tags: []string{"a"}},
+			note: map[string]map[string][]int{"a": {"b": {1}}}}, want: 4},
+		{name: "pointer to struct", value: &inner{Tags: []string{"a"}}, want: 3},
+		{name: "interface slice", value: []interface{}{map[string]interface{}{"a": []interface{}{1}}}, want: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, claimDepth(tt.value, 100))
+		})
+	}
+}
+
+func TestClaimDepth_Cyclic(t *testing.T) {
+	m := map[string]interface{}{}
+	m["self"] = m
+
+	var self interface{}
+	self = &self
+
+	s := []interface{}{nil}
+	s[0] = s
+
+	for name, value := range map[string]interface{}{"map": m, "pointer": &self, "slice": s} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, 5, claimDepth(value, 4))
+
+			err := CheckCustomClaims(map[string]interface{}{"c": value}, ClaimsLimits{MaxClaimsDepth: 4})
+			require.ErrorIs(t, err, ErrClaimsTooDeep)
+		})
+	}
+}
+
+func TestClaimDepth_StopsAtLimit(t *testing.T) {
+	value := map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": 1}}}
+
+	assert.Equal(t, 4, claimDepth(value, 10))
+	assert.Equal(t, 4, claimDepth(value, 4))
+	assert.Greater(t, claimDepth(value, 3), 3)
+	assert.Greater(t, claimDepth(value, 1), 1)
+}
+
+func TestCheckCustomClaims(t *testing.T) {
+	tests := []struct {
+		name    string
+		custom  map[string]interface{}
+		limits  ClaimsLimits
+		wantErr error
+	}{
+		{name: "nil claims", custom: nil, limits: ClaimsLimits{MaxCustomClaims: 1, MaxClaimsDepth: 1}},
+		{name: "within limits", custom: map[string]interface{}{"role": "admin", "tags": []string{"a"}},
+			limits: ClaimsLimits{MaxCustomClaims: 2, MaxClaimsDepth: 2}},
+		{name: "built-in reserved", custom: map[string]interface{}{"uid": 1}, wantErr: ErrReservedClaim},
+		{name: "registered reserved", custom: map[string]interface{}{"iss": "evil"}, wantErr: ErrReservedClaim},
+		{name: "config reserved", custom: map[string]interface{}{"tenant": "a"},
+			limits: ClaimsLimits{ReservedClaims: []string{"tenant"}}, wantErr: ErrReservedClaim},
+		{name: "too many", custom: map[string]interface{}{"a": 1, "b": 2, "c": 3},
+			limits: ClaimsLimits{MaxCustomClaims: 2}, wantErr: ErrTooManyClaims},
+		{name: "too deep", custom: map[string]interface{}{"a": map[string][]string{"b": {"c"}}},
+			limits: ClaimsLimits{MaxClaimsDepth: 2}, wantErr: ErrClaimsTooDeep},
+		{name: "zero limits", custom: map[string]interface{}{"a": 1, "b": 2, "c": [][][]int{{{1}}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckCustomClaims(tt.custom, tt.limits)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestNewToken_Limits(t *testing.T) {
+	user := models.User{ID: 7, Email: "bob@example.com"}
+	app := models.App{Id: 1, Secret: testSecret}
+
+	big := map[string]interface{}{"blob": string(make([]byte, 1024))}
+
+	tests := []struct {
+		name    string
+		custom  map[string]interface{}
+		limits  ClaimsLimits
+		wantErr error
+	}{
+		{name: "custom claims", custom: map[string]interface{}{"role": "admin"}, limits: ClaimsLimits{MaxTokenSize: 1024}},
+		{name: "reserved", custom: map[string]interface{}{"exp": 0}, wantErr: ErrReservedClaim},
+		{name: "too large", custom: big, limits: ClaimsLimits{MaxTokenSize: 512}, wantErr: ErrTokenTooLarge},
+		{name: "size not limited", custom: big},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := NewToken(user, app, time.Hour, tt.custom, tt.limits)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, token)
+				return
+			}
+			require.NoError(t, err)
+
+			parsed, err := jwt.Parse(token, func(*jwt.Token) (interface{}, error) { return testSecret, nil })
+			require.NoError(t, err)
+
+			claims := parsed.Claims.(jwt.MapClaims)
+			for name, value := range tt.custom {
+				assert.Equal(t, value, claims[name])
+			}
+			assert.Equal(t, float64(user.ID), claims["uid"])
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package ntp
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// секунды между эпохой NTP (1900) и эпохой unix (1970)
+const ntpEpochOffset = 2208988800
+
+var (
+	ErrInvalidResponse = errors.New("invalid ntp response")
+	ErrKissOfDeath     = errors.New("ntp server sent kiss-o'-death")
+	ErrUnsynchronized  = errors.New("ntp server clock is unsynchronized")
+)
+
+// Offset returns how far the local clock is behind the server clock using a single SNTP request
+func Offset(ctx context.Context, addr string) (time.Duration, error) {
+	const op = "ntp.Offset"
+
+	var d net.Dialer
+
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	sent := time.Now()
+
+	req := make([]byte, 48)
+	req[0] = 0x1B // LI = 0, VN = 3, Mode = 3 (client)
+	putTimestamp(req[40:48], sent)
+
+	if _, err := conn.Write(req); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	resp := make([]byte, 48)
+	n, err := conn.Read(resp)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	received := time.Now()
+
+	if n < 48 || resp[0]&0x07 != 4 {
+		return 0, fmt.Errorf("%s: %w", op, ErrInvalidResponse)
+	}
+	if resp[1] == 0 {
+		return 0, fmt.Errorf("%s: %w: %s", op, ErrKissOfDeath, string(resp[12:16]))
+	}
+	if resp[0]>>6 == 3 {
+		return 0, fmt.Errorf("%s: %w", op, ErrUnsynchronized)
+	}
+	// сервер обязан вернуть наш transmit timestamp в поле originate
+	if !bytes.Equal(resp[24:32], req[40:48]) {
+		return 0, fmt.Errorf("%s: %w: originate timestamp mismatch", op, ErrInvalidResponse)
+	}
+
+	server, ok := readTimestamp(resp[40:48])
+	if !ok {
+		return 0, fmt.Errorf("%s: %w", op, ErrInvalidResponse)
+	}
+
+	local := sent.Add(received.Sub(sent) / 2)
+
+	return server.Sub(local), nil
+}
+
+func putTimestamp(b []byte, t time.Time) {
+	secs := uint64(t.Unix() + ntpEpochOffset)
+	frac := (uint64(t.Nanosecond()) << 32) / 1e9
+	binary.BigEndian.PutUint32(b[0:4], uint32(secs))
+	binary.BigEndian.PutUint32(b[4:8], uint32(frac))
+}
+
+func readTimestamp(b []byte) (time.Time, bool) {
+	secs := binary.BigEndian.Uint32(b[0:4])
+	frac := binary.BigEndian.Uint32(b[4:8])
+	if secs == 0 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(int64(secs)-ntpEpochOffset, (int64(frac)*1e9)>>32), true
+}
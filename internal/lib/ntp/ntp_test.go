@@ -0,0 +1,97 @@
+package ntp
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serve answers a single request, reply gets the request and fills the response
+func serve(t *testing.T, reply func(req, resp []byte)) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		req := make([]byte, 48)
+		n, addr, err := conn.ReadFrom(req)
+		if err != nil || n < 48 {
+			return
+		}
+
+		resp := make([]byte, 48)
+		resp[0] = 0x24 // LI = 0, VN = 4, Mode = 4 (server)
+		resp[1] = 2    // stratum
+		copy(resp[24:32], req[40:48])
+		putTimestamp(resp[40:48], time.Now().Add(10*time.Second))
+
+		reply(req, resp)
+
+		conn.WriteTo(resp, addr)
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func offset(t *testing.T, addr string) (time.Duration, error) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	return Offset(ctx, addr)
+}
+
+func TestOffset_HappyPath(t *testing.T) {
+	addr := serve(t, func(req, resp []byte) {})
+
+	got, err := offset(t, addr)
+	require.NoError(t, err)
+	assert.InDelta(t, 10*time.Second, got, float64(100*time.Millisecond))
+}
+
+func TestOffset_InvalidReply(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   func(req, resp []byte)
+		wantErr error
+	}{
+		{name: "kiss-o'-death", reply: func(_, resp []byte) {
+			resp[1] = 0
+			copy(resp[12:16], "RATE")
+		}, wantErr: ErrKissOfDeath},
+		{name: "unsynchronized", reply: func(_, resp []byte) { resp[0] |= 0xC0 }, wantErr: ErrUnsynchronized},
+		{name: "originate mismatch", reply: func(_, resp []byte) { resp[31]++ }, wantErr: ErrInvalidResponse},
+		{name: "not a server", reply: func(_, resp []byte) { resp[0] = 0x23 }, wantErr: ErrInvalidResponse},
+		{name: "empty transmit", reply: func(_, resp []byte) { copy(resp[40:48], make([]byte, 8)) }, wantErr: ErrInvalidResponse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := serve(t, tt.reply)
+
+			_, err := offset(t, addr)
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestOffset_Timeout(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = Offset(ctx, conn.LocalAddr().String())
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
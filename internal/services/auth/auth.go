@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"sso/internal/domain/models"
 	jwtlocal "sso/internal/lib"
+	"sso/internal/lib/email"
 	"sso/internal/services/storage"
 	"time"
 
@@ -19,6 +20,13 @@ var (
 	ErrInvalidAppID       = errors.New("invalid appID")
 	ErrUserNotFound       = errors.New("user not found")
 	ErrAppExist           = errors.New("app already exist")
+	ErrInvalidEmail       = errors.New("invalid email")
+	ErrUndeliverableEmail = errors.New("undeliverable email")
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrStaleClaims        = errors.New("token claims are stale")
+	ErrTokenRevoked       = errors.New("token revoked")
+	ErrEmptyFilter        = errors.New("empty revoke filter")
+	ErrInvalidFilter      = errors.New("invalid revoke filter")
 )
 
 type Auth struct {
@@ -27,16 +35,22 @@ type Auth struct {
 	usrProvider UserProvider
 	appProvider AppProvider
 	appSaver    AppSaver
+	revoker     TokenRevoker
+	emailValid  EmailValidator
 	tokenTTL    time.Duration
+	limits      jwtlocal.ClaimsLimits
+	claims      map[string]interface{}
 }
 
 type UserSaver interface {
 	SaveUser(ctx context.Context, email string, passHash []byte) (uid int64, err error)
+	SetAdmin(ctx context.Context, userID int64, isAdmin bool) (rolesVer int64, err error)
 }
 
 type UserProvider interface {
 	User(ctx context.Context, email string) (modelU models.User, err error)
 	IsAdmin(ctx context.Context, userID int64) (isAdmin bool, err error)
+	RolesVersion(ctx context.Context, userID int64) (rolesVer int64, err error)
 }
 
 type AppSaver interface {
@@ -47,32 +61,45 @@ type AppProvider interface {
 	App(ctx context.Context, appID int64) (modelA models.App, err error)
 }
 
+type TokenRevoker interface {
+	RevokeAppTokens(ctx context.Context, appID int64, before time.Time) (affected int64, err error)
+}
+
+type EmailValidator interface {
+	Validate(ctx context.Context, email string) (normalized string, err error)
+}
+
 // New returns a new object of the Auth struct
 func NewAuth(log *slog.Logger, usrSaver UserSaver,
 	usrProvider UserProvider, appProvider AppProvider,
-	appSaver AppSaver, tokenTTL time.Duration) *Auth {
+	appSaver AppSaver, revoker TokenRevoker, emailValid EmailValidator, tokenTTL time.Duration,
+	limits jwtlocal.ClaimsLimits, claims map[string]interface{}) *Auth {
 	return &Auth{
 		log:         log,
 		usrSaver:    usrSaver,
 		usrProvider: usrProvider,
 		appProvider: appProvider,
 		appSaver:    appSaver,
+		revoker:     revoker,
+		emailValid:  emailValid,
 		tokenTTL:    tokenTTL,
+		limits:      limits,
+		claims:      claims,
 	}
 }
 
 func (a *Auth) Login(ctx context.Context,
-	email string, password string, appID int64) (string, error) {
+	userEmail string, password string, appID int64) (string, error) {
 	const op = "auth.Login"
 
 	log := a.log.With(
 		slog.String("op", op),
-		slog.String("email", email),
+		slog.String("email", userEmail),
 	)
 
 	log.Info("attempting to login user")
 
-	user, err := a.usrProvider.User(ctx, email)
+	user, err := a.user(ctx, userEmail)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
 			log.Error("not corrected login/password")
@@ -94,32 +121,73 @@ func (a *Auth) Login(ctx context.Context,
 
 	log.Info("successfully login user")
 
-	token, err := jwtlocal.NewToken(user, app, a.tokenTTL)
+	token, err := jwtlocal.NewToken(user, app, a.tokenTTL, a.claims, a.limits)
 	if err != nil {
-		log.Error("cannot generate token")
+		log.Error("cannot generate token: " + err.Error())
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
 	return token, nil
 }
 
-func (a *Auth) RegisterNewUser(ctx context.Context, email string, password string) (int64, error) {
+// user looks up the normalized email first and falls back to the raw one
+// for users registered before emails were normalized
+func (a *Auth) user(ctx context.Context, userEmail string) (models.User, error) {
+	normalized, err := email.Normalize(userEmail)
+	if err != nil || normalized == userEmail {
+		return a.usrProvider.User(ctx, userEmail)
+	}
+
+	user, err := a.usrProvider.User(ctx, normalized)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return a.usrProvider.User(ctx, userEmail)
+	}
+
+	return user, err
+}
+
+func (a *Auth) RegisterNewUser(ctx context.Context, userEmail string, password string) (int64, error) {
 	const op = "auth.RegisterNewUser"
 
 	log := a.log.With(
 		slog.String("op", op),
-		slog.String("email", email),
+		slog.String("email", userEmail),
 	)
 
 	log.Info("registering new user")
 
+	rawEmail := userEmail
+
+	userEmail, err := a.emailValid.Validate(ctx, userEmail)
+	if err != nil {
+		if errors.Is(err, email.ErrUndeliverableEmail) {
+			log.Error("email is undeliverable")
+			return 0, fmt.Errorf("%s: %w", op, ErrUndeliverableEmail)
+		}
+		log.Error("invalid email")
+		return 0, fmt.Errorf("%s: %w", op, ErrInvalidEmail)
+	}
+
+	// IDN домены миграция не нормализует, такие пользователи хранятся как ввели
+	if userEmail != rawEmail {
+		_, err := a.usrProvider.User(ctx, rawEmail)
+		if err == nil {
+			log.Error("user already exist")
+			return 0, fmt.Errorf("%s: %w", op, ErrUserExists)
+		}
+		if !errors.Is(err, storage.ErrUserNotFound) {
+			log.Error("failed to get user: " + err.Error())
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
 	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		log.Error("failed to generate password hash")
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	id, err := a.usrSaver.SaveUser(ctx, email, passHash)
+	id, err := a.usrSaver.SaveUser(ctx, userEmail, passHash)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserExist) {
 			log.Error("user already exist")
@@ -173,3 +241,113 @@ func (a *Auth) CreateApp(ctx context.Context, name string, secret string) (int64
 
 	return appId, nil
 }
+
+func (a *Auth) SetAdmin(ctx context.Context, userId int64, isAdmin bool) error {
+	const op = "auth.SetAdmin"
+
+	log := a.log.With(slog.String("op", op), slog.Int64("userId", userId))
+
+	rolesVer, err := a.usrSaver.SetAdmin(ctx, userId, isAdmin)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Error("user not found")
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		log.Error("failed to change user role: " + err.Error())
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("success change user role", slog.Bool("isAdmin", isAdmin), slog.Int64("rolesVer", rolesVer))
+
+	return nil
+}
+
+// Introspect verifies the token and checks that its roles_ver matches the stored one.
+// On ErrStaleClaims the claims are still returned, the client should login again to refresh them.
+// Not served over gRPC yet: the proto contract has no Introspect RPC
+func (a *Auth) Introspect(ctx context.Context, token string) (models.TokenClaims, error) {
+	const op = "auth.Introspect"
+
+	log := a.log.With(slog.String("op", op))
+
+	var app models.App
+
+	claims, err := jwtlocal.ParseToken(token, func(appID int64) ([]byte, error) {
+		var err error
+		app, err = a.appProvider.App(ctx, appID)
+		if err != nil {
+			return nil, err
+		}
+		return app.Secret, nil
+	})
+	if err != nil {
+		log.Error("failed to parse token: " + err.Error())
+		return models.TokenClaims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	if claims.IssuedAt.Before(app.RevokedBefore) {
+		log.Info("token revoked by app epoch", slog.Int("appId", app.Id))
+		return models.TokenClaims{}, fmt.Errorf("%s: %w", op, ErrTokenRevoked)
+	}
+
+	rolesVer, err := a.usrProvider.RolesVersion(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Error("user not found")
+			return models.TokenClaims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+		log.Error("failed to get roles version: " + err.Error())
+		return models.TokenClaims{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if claims.RolesVer != rolesVer {
+		log.Info("token claims are stale", slog.Int64("tokenRolesVer", claims.RolesVer), slog.Int64("rolesVer", rolesVer))
+		return claims, fmt.Errorf("%s: %w", op, ErrStaleClaims)
+	}
+
+	return claims, nil
+}
+
+// RevokeTokens revokes all tokens matching the filter by moving the revocation epoch
+// of the app forward, tokens issued later stay valid.
+// Epochs can not be moved back, so IssuedBefore in the future is rejected.
+// iat is chosen by whoever signs the token: after a secret leak the attacker can mint
+// tokens with any iat, so revocation must go together with rotating the app secret
+func (a *Auth) RevokeTokens(ctx context.Context, filter models.RevokeFilter) error {
+	const op = "auth.RevokeTokens"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("appId", filter.AppID),
+	)
+
+	if filter.AppID == 0 && filter.IssuedBefore.IsZero() {
+		log.Error("revoke filter is empty")
+		return fmt.Errorf("%s: %w", op, ErrEmptyFilter)
+	}
+
+	if filter.IssuedBefore.After(time.Now()) {
+		log.Error("issued before is in the future", slog.Time("issuedBefore", filter.IssuedBefore))
+		return fmt.Errorf("%s: %w: issued before is in the future", op, ErrInvalidFilter)
+	}
+
+	before := filter.IssuedBefore
+	if before.IsZero() {
+		// iat хранится в секундах, поэтому отзываем и токены выпущенные в текущую секунду
+		before = time.Now().Truncate(time.Second).Add(time.Second)
+	}
+
+	affected, err := a.revoker.RevokeAppTokens(ctx, filter.AppID, before)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Error("app not found")
+			return fmt.Errorf("%s: %w", op, ErrInvalidAppID)
+		}
+		log.Error("failed to revoke app tokens: " + err.Error())
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("success revoke app tokens", slog.Time("before", before), slog.Int64("apps", affected))
+
+	return nil
+}
@@ -0,0 +1,371 @@
+package auth_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sso/internal/domain/models"
+	jwtlocal "sso/internal/lib"
+	"sso/internal/lib/email"
+	"sso/internal/services/auth"
+	"sso/internal/services/storage"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	testEmail    = "bob@example.com"
+	testPassword = "password"
+	testSecret   = "0123456789abcdef0123456789abcdef"
+)
+
+// fakeStorage keeps users and apps in memory
+type fakeStorage struct {
+	mu     sync.Mutex
+	users  map[int64]models.User
+	admins map[int64]bool
+	apps   map[int64]models.App
+}
+
+func newFakeStorage(t *testing.T) *fakeStorage {
+	t.Helper()
+
+	passHash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	return &fakeStorage{
+		users:  map[int64]models.User{1: {ID: 1, Email: testEmail, PassHash: passHash}},
+		admins: map[int64]bool{},
+		apps:   map[int64]models.App{1: {Id: 1, Name: "test", Secret: []byte(testSecret)}},
+	}
+}
+
+func (s *fakeStorage) SaveUser(_ context.Context, email string, passHash []byte) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			return 0, storage.ErrUserExist
+		}
+	}
+
+	id := int64(len(s.users) + 1)
+	s.users[id] = models.User{ID: id, Email: email, PassHash: passHash}
+
+	return id, nil
+}
+
+func (s *fakeStorage) SetAdmin(_ context.Context, userID int64, isAdmin bool) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return 0, storage.ErrUserNotFound
+	}
+
+	user.RolesVer++
+	s.users[userID] = user
+	s.admins[userID] = isAdmin
+
+	return user.RolesVer, nil
+}
+
+func (s *fakeStorage) User(_ context.Context, email string) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+
+	return models.User{}, storage.ErrUserNotFound
+}
+
+func (s *fakeStorage) IsAdmin(_ context.Context, userID int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[userID]; !ok {
+		return false, storage.ErrUserNotFound
+	}
+
+	return s.admins[userID], nil
+}
+
+func (s *fakeStorage) RolesVersion(_ context.Context, userID int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return 0, storage.ErrUserNotFound
+	}
+
+	return user.RolesVer, nil
+}
+
+func (s *fakeStorage) App(_ context.Context, appID int64) (models.App, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	app, ok := s.apps[appID]
+	if !ok {
+		return models.App{}, storage.ErrAppNotFound
+	}
+
+	return app, nil
+}
+
+func (s *fakeStorage) SaveApp(_ context.Context, name string, secret string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := int64(len(s.apps) + 1)
+	s.apps[id] = models.App{Id: int(id), Name: name, Secret: []byte(secret)}
+
+	return id, nil
+}
+
+func (s *fakeStorage) RevokeAppTokens(_ context.Context, appID int64, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var affected int64
+	for id, app := range s.apps {
+		if appID != 0 && id != appID {
+			continue
+		}
+		if before.After(app.RevokedBefore) {
+			app.RevokedBefore = before
+			s.apps[id] = app
+		}
+		affected++
+	}
+	if appID != 0 && affected == 0 {
+		return 0, storage.ErrAppNotFound
+	}
+
+	return affected, nil
+}
+
+func newTestAuth(st *fakeStorage) *auth.Auth {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	emailValid := email.NewValidator(false, time.Second, time.Hour)
+
+	return auth.NewAuth(log, st, st, st, st, st, emailValid, time.Hour, jwtlocal.ClaimsLimits{}, nil)
+}
+
+func TestIntrospect_HappyPath(t *testing.T) {
+	ctx := context.Background()
+	a := newTestAuth(newFakeStorage(t))
+
+	token, err := a.Login(ctx, testEmail, testPassword, 1)
+	require.NoError(t, err)
+
+	claims, err := a.Introspect(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), claims.UserID)
+	assert.Equal(t, 1, claims.AppID)
+	assert.Zero(t, claims.RolesVer)
+}
+
+func TestIntrospect_StaleAfterSetAdmin(t *testing.T) {
+	ctx := context.Background()
+	a := newTestAuth(newFakeStorage(t))
+
+	oldToken, err := a.Login(ctx, testEmail, testPassword, 1)
+	require.NoError(t, err)
+
+	require.NoError(t, a.SetAdmin(ctx, 1, true))
+
+	claims, err := a.Introspect(ctx, oldToken)
+	require.ErrorIs(t, err, auth.ErrStaleClaims)
+	assert.Equal(t, int64(1), claims.UserID)
+
+	// новый логин выдает токен с актуальной версией ролей
+	newToken, err := a.Login(ctx, testEmail, testPassword, 1)
+	require.NoError(t, err)
+
+	claims, err = a.Introspect(ctx, newToken)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), claims.RolesVer)
+}
+
+func TestIntrospect_InvalidToken(t *testing.T) {
+	a := newTestAuth(newFakeStorage(t))
+
+	_, err := a.Introspect(context.Background(), "not.a.token")
+	require.ErrorIs(t, err, auth.ErrInvalidToken)
+}
+
+func TestSetAdmin_UserNotFound(t *testing.T) {
+	a := newTestAuth(newFakeStorage(t))
+
+	err := a.SetAdmin(context.Background(), 42, true)
+	require.ErrorIs(t, err, auth.ErrUserNotFound)
+}
+
+func TestIntrospect_LegacyToken(t *testing.T) {
+	ctx := context.Background()
+	st := newFakeStorage(t)
+	a := newTestAuth(st)
+
+	// токены до этой серии: без iat и roles_ver
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"uid":    1,
+		"email":  testEmail,
+		"app_id": 1,
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(testSecret))
+	require.NoError(t, err)
+
+	claims, err := a.Introspect(ctx, legacy)
+	require.NoError(t, err)
+	assert.Zero(t, claims.RolesVer)
+
+	// без iat токен отзывается любой эпохой, даже самой ранней
+	require.NoError(t, a.RevokeTokens(ctx, models.RevokeFilter{AppID: 1, IssuedBefore: time.Unix(1, 0)}))
+
+	_, err = a.Introspect(ctx, legacy)
+	require.ErrorIs(t, err, auth.ErrTokenRevoked)
+}
+
+func TestRevokeTokens(t *testing.T) {
+	tests := []struct {
+		name        string
+		filter      models.RevokeFilter
+		wantRevoked bool
+	}{
+		{name: "by app", filter: models.RevokeFilter{AppID: 1}, wantRevoked: true},
+		{name: "all apps before now", filter: models.RevokeFilter{IssuedBefore: time.Now()}, wantRevoked: true},
+		{name: "window before token", filter: models.RevokeFilter{AppID: 1, IssuedBefore: time.Now().Add(-time.Hour)}},
+		{name: "all apps window before token", filter: models.RevokeFilter{IssuedBefore: time.Now().Add(-time.Hour)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			a := newTestAuth(newFakeStorage(t))
+
+			token, err := a.Login(ctx, testEmail, testPassword, 1)
+			require.NoError(t, err)
+
+			require.NoError(t, a.RevokeTokens(ctx, tt.filter))
+
+			_, err = a.Introspect(ctx, token)
+			if tt.wantRevoked {
+				require.ErrorIs(t, err, auth.ErrTokenRevoked)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRevokeTokens_InvalidFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  models.RevokeFilter
+		wantErr error
+	}{
+		{name: "empty", filter: models.RevokeFilter{}, wantErr: auth.ErrEmptyFilter},
+		{name: "future", filter: models.RevokeFilter{AppID: 1, IssuedBefore: time.Now().AddDate(1, 0, 0)}, wantErr: auth.ErrInvalidFilter},
+		{name: "unknown app", filter: models.RevokeFilter{AppID: 42}, wantErr: auth.ErrInvalidAppID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := newFakeStorage(t)
+			a := newTestAuth(st)
+
+			err := a.RevokeTokens(context.Background(), tt.filter)
+			require.ErrorIs(t, err, tt.wantErr)
+			assert.True(t, st.apps[1].RevokedBefore.IsZero())
+		})
+	}
+}
+
+func TestRegisterNewUser_LegacyRawEmail(t *testing.T) {
+	ctx := context.Background()
+	st := newFakeStorage(t)
+	a := newTestAuth(st)
+
+	// пользователь сохранен до нормализации email
+	legacy := "alice@пример.рф"
+	_, err := st.SaveUser(ctx, legacy, []byte("hash"))
+	require.NoError(t, err)
+
+	_, err = a.RegisterNewUser(ctx, legacy, testPassword)
+	require.ErrorIs(t, err, auth.ErrUserExists)
+
+	id, err := a.RegisterNewUser(ctx, "carol@Example.com", testPassword)
+	require.NoError(t, err)
+	assert.Equal(t, "carol@example.com", st.users[id].Email)
+
+	_, err = a.RegisterNewUser(ctx, "carol@EXAMPLE.com", testPassword)
+	require.ErrorIs(t, err, auth.ErrUserExists)
+}
+
+func TestLogin_LegacyRawEmail(t *testing.T) {
+	ctx := context.Background()
+	st := newFakeStorage(t)
+	a := newTestAuth(st)
+
+	legacy := "alice@пример.рф"
+	_, err := st.SaveUser(ctx, legacy, st.users[1].PassHash)
+	require.NoError(t, err)
+
+	_, err = a.Login(ctx, legacy, testPassword, 1)
+	require.NoError(t, err)
+}
+
+func TestLogin_StaticClaims(t *testing.T) {
+	ctx := context.Background()
+	st := newFakeStorage(t)
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	emailValid := email.NewValidator(false, time.Second, time.Hour)
+
+	limits := jwtlocal.ClaimsLimits{MaxClaimsDepth: 2, ReservedClaims: []string{"tenant_id"}}
+
+	tests := []struct {
+		name    string
+		claims  map[string]interface{}
+		wantErr error
+	}{
+		{name: "added to token", claims: map[string]interface{}{"tenant": "acme", "scopes": []interface{}{"read"}}},
+		{name: "reserved from config", claims: map[string]interface{}{"tenant_id": 1}, wantErr: jwtlocal.ErrReservedClaim},
+		{name: "built-in reserved", claims: map[string]interface{}{"uid": 2}, wantErr: jwtlocal.ErrReservedClaim},
+		{name: "too deep", claims: map[string]interface{}{"a": map[string]interface{}{"b": []interface{}{1}}}, wantErr: jwtlocal.ErrClaimsTooDeep},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := auth.NewAuth(log, st, st, st, st, st, emailValid, time.Hour, limits, tt.claims)
+
+			token, err := a.Login(ctx, testEmail, testPassword, 1)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			parsed, err := jwt.Parse(token, func(*jwt.Token) (interface{}, error) { return []byte(testSecret), nil })
+			require.NoError(t, err)
+
+			claims := parsed.Claims.(jwt.MapClaims)
+			for name, value := range tt.claims {
+				assert.Equal(t, value, claims[name])
+			}
+		})
+	}
+}
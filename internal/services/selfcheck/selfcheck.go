@@ -0,0 +1,218 @@
+package selfcheck
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sso/internal/config"
+	"sso/internal/domain/models"
+	jwtlocal "sso/internal/lib"
+	"sso/internal/lib/ntp"
+	"strings"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+const envProd = "prod"
+
+type Check struct {
+	Name    string
+	Status  Status
+	Message string
+}
+
+type Report struct {
+	CheckedAt time.Time
+	Checks    []Check
+}
+
+// OK reports whether no check failed, warnings do not count
+func (r Report) OK() bool {
+	for _, check := range r.Checks {
+		if check.Status == StatusFail {
+			return false
+		}
+	}
+
+	return true
+}
+
+type Storage interface {
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (version int64, err error)
+	Apps(ctx context.Context) (apps []models.App, err error)
+}
+
+type SelfCheck struct {
+	log           *slog.Logger
+	storage       Storage
+	cfg           *config.Config
+	schemaVersion int64
+}
+
+// New returns a new object of the SelfCheck struct
+func New(log *slog.Logger, storage Storage, cfg *config.Config, schemaVersion int64) *SelfCheck {
+	return &SelfCheck{
+		log:           log,
+		storage:       storage,
+		cfg:           cfg,
+		schemaVersion: schemaVersion,
+	}
+}
+
+// Run executes all checks and logs every result, it never stops the server by itself
+func (s *SelfCheck) Run(ctx context.Context) Report {
+	const op = "selfcheck.Run"
+
+	log := s.log.With(slog.String("op", op))
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.SelfCheck.Timeout)
+	defer cancel()
+
+	report := Report{CheckedAt: time.Now()}
+	report.Checks = append(report.Checks, s.checkDB(ctx), s.checkSchema(ctx))
+	report.Checks = append(report.Checks, s.checkKeys(ctx))
+	report.Checks = append(report.Checks, s.checkClock(ctx))
+	report.Checks = append(report.Checks, s.checkConfig()...)
+
+	for _, check := range report.Checks {
+		attrs := []any{slog.String("check", check.Name), slog.String("message", check.Message)}
+		switch check.Status {
+		case StatusOK:
+			log.Info("self-check passed", attrs...)
+		case StatusWarn:
+			log.Warn("self-check warning", attrs...)
+		case StatusFail:
+			log.Error("self-check failed", attrs...)
+		}
+	}
+
+	log.Info("self-check finished", slog.Bool("ok", report.OK()), slog.Int("checks", len(report.Checks)))
+
+	return report
+}
+
+func (s *SelfCheck) checkDB(ctx context.Context) Check {
+	start := time.Now()
+
+	if err := s.storage.Ping(ctx); err != nil {
+		return Check{Name: "db", Status: StatusFail, Message: err.Error()}
+	}
+
+	return Check{Name: "db", Status: StatusOK, Message: fmt.Sprintf("ping %s", time.Since(start).Round(time.Microsecond))}
+}
+
+// checkSchema compares the applied migrations with the embedded ones
+func (s *SelfCheck) checkSchema(ctx context.Context) Check {
+	version, err := s.storage.SchemaVersion(ctx)
+	if err != nil {
+		return Check{Name: "schema", Status: StatusFail, Message: err.Error()}
+	}
+
+	switch {
+	case version < s.schemaVersion:
+		return Check{Name: "schema", Status: StatusFail,
+			Message: fmt.Sprintf("version %d is older than expected %d, run migrations", version, s.schemaVersion)}
+	case version > s.schemaVersion:
+		return Check{Name: "schema", Status: StatusWarn,
+			Message: fmt.Sprintf("version %d is newer than expected %d", version, s.schemaVersion)}
+	}
+
+	return Check{Name: "schema", Status: StatusOK, Message: fmt.Sprintf("version %d", version)}
+}
+
+func (s *SelfCheck) checkKeys(ctx context.Context) Check {
+	apps, err := s.storage.Apps(ctx)
+	if err != nil {
+		return Check{Name: "keys", Status: StatusFail, Message: err.Error()}
+	}
+
+	var empty, short []string
+	for _, app := range apps {
+		switch {
+		case len(app.Secret) == 0:
+			empty = append(empty, app.Name)
+		case len(app.Secret) < jwtlocal.MinSecretLen:
+			short = append(short, app.Name)
+		}
+	}
+
+	var problems []string
+	if len(empty) > 0 {
+		problems = append(problems, "apps without secret: "+strings.Join(empty, ", "))
+	}
+	if len(short) > 0 {
+		problems = append(problems, fmt.Sprintf("apps with secret shorter than %d bytes: %s",
+			jwtlocal.MinSecretLen, strings.Join(short, ", ")))
+	}
+	// короткий секрет есть у тестового app из сида, вне prod это только предупреждение
+	if len(empty) > 0 || (len(short) > 0 && s.cfg.Env == envProd) {
+		return Check{Name: "keys", Status: StatusFail, Message: strings.Join(problems, "; ")}
+	}
+	if len(problems) > 0 {
+		return Check{Name: "keys", Status: StatusWarn, Message: strings.Join(problems, "; ")}
+	}
+
+	return Check{Name: "keys", Status: StatusOK, Message: fmt.Sprintf("%d apps have a valid secret", len(apps))}
+}
+
+func (s *SelfCheck) checkClock(ctx context.Context) Check {
+	if s.cfg.SelfCheck.NTPServer == "" {
+		return Check{Name: "clock", Status: StatusWarn, Message: "ntp server is not configured"}
+	}
+
+	// ntp часто закрыт файрволом, не держим старт дольше ntp_timeout
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.SelfCheck.NTPTimeout)
+	defer cancel()
+
+	offset, err := ntp.Offset(ctx, s.cfg.SelfCheck.NTPServer)
+	if err != nil {
+		return Check{Name: "clock", Status: StatusWarn, Message: "ntp is unavailable: " + err.Error()}
+	}
+
+	drift := offset.Abs()
+	if drift > s.cfg.SelfCheck.MaxClockDrift {
+		return Check{Name: "clock", Status: StatusFail,
+			Message: fmt.Sprintf("drift %s exceeds %s", drift, s.cfg.SelfCheck.MaxClockDrift)}
+	}
+
+	return Check{Name: "clock", Status: StatusOK, Message: fmt.Sprintf("drift %s", drift)}
+}
+
+func (s *SelfCheck) checkConfig() []Check {
+	var warnings []string
+
+	if s.cfg.TokenTTL <= 0 {
+		warnings = append(warnings, "token_ttl is not positive")
+	}
+	if s.cfg.GRPC.Timeout <= 0 {
+		warnings = append(warnings, "grpc.timeout is not set")
+	}
+	if s.cfg.DB.SSLmode == "disable" && s.cfg.Env == envProd {
+		warnings = append(warnings, "db.sslmode is disabled in prod")
+	}
+	if !s.cfg.Email.CheckMX && s.cfg.Env == envProd {
+		warnings = append(warnings, "email.check_mx is disabled in prod")
+	}
+	if s.cfg.Token.MaxSize <= 0 {
+		warnings = append(warnings, "token.max_size is not limited")
+	}
+
+	if len(warnings) == 0 {
+		return []Check{{Name: "config", Status: StatusOK, Message: "no warnings"}}
+	}
+
+	checks := make([]Check, 0, len(warnings))
+	for _, warning := range warnings {
+		checks = append(checks, Check{Name: "config", Status: StatusWarn, Message: warning})
+	}
+
+	return checks
+}
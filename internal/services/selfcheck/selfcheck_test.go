@@ -0,0 +1,205 @@
+package selfcheck
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sso/internal/config"
+	"sso/internal/domain/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const expectedVersion = 20261014130000
+
+type fakeStorage struct {
+	pingErr    error
+	version    int64
+	versionErr error
+	apps       []models.App
+}
+
+func (s *fakeStorage) Ping(context.Context) error {
+	return s.pingErr
+}
+
+func (s *fakeStorage) SchemaVersion(context.Context) (int64, error) {
+	return s.version, s.versionErr
+}
+
+func (s *fakeStorage) Apps(context.Context) ([]models.App, error) {
+	return s.apps, nil
+}
+
+func validConfig() *config.Config {
+	return &config.Config{
+		Env:       "local",
+		TokenTTL:  time.Hour,
+		GRPC:      config.GRPCConfig{Port: 8080, Timeout: time.Second},
+		Token:     config.TokenConfig{MaxSize: 8192},
+		SelfCheck: config.SelfCheckConfig{Timeout: time.Second, NTPTimeout: time.Second},
+	}
+}
+
+func newTestSelfCheck(st Storage, cfg *config.Config) *SelfCheck {
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), st, cfg, expectedVersion)
+}
+
+func findCheck(t *testing.T, report Report, name string) Check {
+	t.Helper()
+
+	for _, check := range report.Checks {
+		if check.Name == name {
+			return check
+		}
+	}
+
+	t.Fatalf("check %s not found in report", name)
+	return Check{}
+}
+
+func TestCheckDB(t *testing.T) {
+	tests := []struct {
+		name    string
+		storage *fakeStorage
+		want    Status
+	}{
+		{name: "reachable", storage: &fakeStorage{version: expectedVersion}, want: StatusOK},
+		{name: "unreachable", storage: &fakeStorage{pingErr: errors.New("connection refused")}, want: StatusFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := newTestSelfCheck(tt.storage, validConfig()).Run(context.Background())
+			check := findCheck(t, report, "db")
+			assert.Equal(t, tt.want, check.Status)
+			if tt.want == StatusOK {
+				assert.Contains(t, check.Message, "ping")
+			}
+		})
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		storage *fakeStorage
+		want    Status
+	}{
+		{name: "equal", storage: &fakeStorage{version: expectedVersion}, want: StatusOK},
+		{name: "older", storage: &fakeStorage{version: expectedVersion - 1}, want: StatusFail},
+		{name: "newer", storage: &fakeStorage{version: expectedVersion + 1}, want: StatusWarn},
+		{name: "error", storage: &fakeStorage{versionErr: errors.New("no goose table")}, want: StatusFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := newTestSelfCheck(tt.storage, validConfig()).Run(context.Background())
+			assert.Equal(t, tt.want, findCheck(t, report, "schema").Status)
+		})
+	}
+}
+
+func TestCheckKeys(t *testing.T) {
+	validSecret := []byte("0123456789abcdef0123456789abcdef")
+
+	tests := []struct {
+		name     string
+		env      string
+		apps     []models.App
+		want     Status
+		contains string
+	}{
+		{name: "valid", apps: []models.App{{Name: "a", Secret: validSecret}}, want: StatusOK},
+		{name: "no apps", apps: nil, want: StatusOK},
+		{name: "empty secret", apps: []models.App{{Name: "a", Secret: validSecret}, {Name: "b"}},
+			want: StatusFail, contains: "without secret: b"},
+		{name: "short secret local", env: "local", apps: []models.App{{Name: "test", Secret: []byte("test-secret")}},
+			want: StatusWarn, contains: "shorter than 32 bytes: test"},
+		{name: "short secret dev", env: "dev", apps: []models.App{{Name: "test", Secret: []byte("test-secret")}},
+			want: StatusWarn, contains: "shorter than 32 bytes: test"},
+		{name: "short secret prod", env: "prod", apps: []models.App{{Name: "test", Secret: []byte("test-secret")}},
+			want: StatusFail, contains: "shorter than 32 bytes: test"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			if tt.env != "" {
+				cfg.Env = tt.env
+			}
+
+			st := &fakeStorage{version: expectedVersion, apps: tt.apps}
+			check := findCheck(t, newTestSelfCheck(st, cfg).Run(context.Background()), "keys")
+			assert.Equal(t, tt.want, check.Status)
+			assert.Contains(t, check.Message, tt.contains)
+		})
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cfg *config.Config)
+		want   []string
+	}{
+		{name: "no warnings", modify: func(*config.Config) {}},
+		{name: "token ttl", modify: func(cfg *config.Config) { cfg.TokenTTL = 0 }, want: []string{"token_ttl is not positive"}},
+		{name: "grpc timeout", modify: func(cfg *config.Config) { cfg.GRPC.Timeout = 0 }, want: []string{"grpc.timeout is not set"}},
+		{name: "token size", modify: func(cfg *config.Config) { cfg.Token.MaxSize = 0 }, want: []string{"token.max_size is not limited"}},
+		{name: "prod", modify: func(cfg *config.Config) {
+			cfg.Env = "prod"
+			cfg.DB.SSLmode = "disable"
+		}, want: []string{"db.sslmode is disabled in prod", "email.check_mx is disabled in prod"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(cfg)
+
+			checks := newTestSelfCheck(&fakeStorage{}, cfg).checkConfig()
+
+			if len(tt.want) == 0 {
+				require.Len(t, checks, 1)
+				assert.Equal(t, StatusOK, checks[0].Status)
+				return
+			}
+
+			var got []string
+			for _, check := range checks {
+				assert.Equal(t, StatusWarn, check.Status)
+				got = append(got, check.Message)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCheckClock_NotConfigured(t *testing.T) {
+	report := newTestSelfCheck(&fakeStorage{version: expectedVersion}, validConfig()).Run(context.Background())
+	assert.Equal(t, StatusWarn, findCheck(t, report, "clock").Status)
+}
+
+func TestReport_OK(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks []Check
+		want   bool
+	}{
+		{name: "empty", want: true},
+		{name: "all ok", checks: []Check{{Status: StatusOK}, {Status: StatusOK}}, want: true},
+		{name: "warnings pass", checks: []Check{{Status: StatusOK}, {Status: StatusWarn}}, want: true},
+		{name: "fail", checks: []Check{{Status: StatusWarn}, {Status: StatusFail}}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Report{Checks: tt.checks}.OK())
+		})
+	}
+}
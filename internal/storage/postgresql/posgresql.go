@@ -8,6 +8,7 @@ import (
 	"sso/internal/config"
 	"sso/internal/domain/models"
 	"sso/internal/services/storage"
+	"time"
 
 	"github.com/lib/pq"
 )
@@ -15,6 +16,7 @@ import (
 const (
 	usersTable = "users"
 	appsTable  = "apps"
+	gooseTable = "goose_db_version"
 )
 
 type Storage struct {
@@ -66,12 +68,12 @@ func (s *Storage) User(ctx context.Context, email string) (models.User, error) {
 
 	var us models.User
 
-	stmt, err := s.db.Prepare(fmt.Sprintf("SELECT id, email, password_hash FROM %s WHERE email=$1", usersTable))
+	stmt, err := s.db.Prepare(fmt.Sprintf("SELECT id, email, password_hash, roles_ver FROM %s WHERE email=$1", usersTable))
 	if err != nil {
 		return us, fmt.Errorf("%s: %s", op, err.Error())
 	}
 
-	if err = stmt.QueryRowContext(ctx, email).Scan(&us.ID, &us.Email, &us.PassHash); err != nil {
+	if err = stmt.QueryRowContext(ctx, email).Scan(&us.ID, &us.Email, &us.PassHash, &us.RolesVer); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return us, storage.ErrUserNotFound
 		}
@@ -87,14 +89,14 @@ func (s *Storage) App(ctx context.Context, appID int64) (models.App, error) {
 
 	var app models.App
 
-	stmt, err := s.db.Prepare(fmt.Sprintf("SELECT id, name, secret FROM %s WHERE id=$1", appsTable))
+	stmt, err := s.db.Prepare(fmt.Sprintf("SELECT id, name, secret, revoked_before FROM %s WHERE id=$1", appsTable))
 	if err != nil {
 		return app, fmt.Errorf("%s: %s", op, err.Error())
 	}
 
 	result := stmt.QueryRowContext(ctx, appID)
 
-	if err = result.Scan(&app.Id, &app.Name, &app.Secret); err != nil {
+	if err = result.Scan(&app.Id, &app.Name, &app.Secret, &app.RevokedBefore); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return app, storage.ErrAppNotFound
 		}
@@ -147,3 +149,128 @@ func (s *Storage) SaveApp(ctx context.Context, name string, secret string) (int6
 
 	return id, nil
 }
+
+func (s *Storage) RolesVersion(ctx context.Context, userID int64) (int64, error) {
+	const op = "storage.postgresql.RolesVersion"
+
+	var res int64
+
+	stmt, err := s.db.Prepare(fmt.Sprintf("SELECT roles_ver FROM %s WHERE id=$1", usersTable))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %s", op, err.Error())
+	}
+
+	if err = stmt.QueryRowContext(ctx, userID).Scan(&res); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, storage.ErrUserNotFound
+		}
+
+		return 0, fmt.Errorf("%s: %s", op, err.Error())
+	}
+
+	return res, nil
+}
+
+// SetAdmin changes the user role and bumps roles_ver so already issued tokens become stale
+func (s *Storage) SetAdmin(ctx context.Context, userID int64, isAdmin bool) (int64, error) {
+	const op = "storage.postgresql.SetAdmin"
+
+	stmt, err := s.db.Prepare(fmt.Sprintf("UPDATE %s SET is_admin=$1, roles_ver=roles_ver+1 WHERE id=$2 RETURNING roles_ver", usersTable))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var rolesVer int64
+	if err := stmt.QueryRowContext(ctx, isAdmin, userID).Scan(&rolesVer); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, storage.ErrUserNotFound
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return rolesVer, nil
+}
+
+// RevokeAppTokens moves the revocation epoch of the app forward, appID 0 affects all apps
+func (s *Storage) RevokeAppTokens(ctx context.Context, appID int64, before time.Time) (int64, error) {
+	const op = "storage.postgresql.RevokeAppTokens"
+
+	stmt, err := s.db.Prepare(fmt.Sprintf("UPDATE %s SET revoked_before=GREATEST(revoked_before, $1) WHERE $2=0 OR id=$2", appsTable))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmt.ExecContext(ctx, before, appID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if appID != 0 && affected == 0 {
+		return 0, storage.ErrAppNotFound
+	}
+
+	return affected, nil
+}
+
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "storage.postgresql.Ping"
+
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SchemaVersion returns the latest applied goose migration
+func (s *Storage) SchemaVersion(ctx context.Context) (int64, error) {
+	const op = "storage.postgresql.SchemaVersion"
+
+	var res sql.NullInt64
+
+	stmt, err := s.db.Prepare(fmt.Sprintf("SELECT MAX(version_id) FROM %s WHERE is_applied", gooseTable))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %s", op, err.Error())
+	}
+
+	if err = stmt.QueryRowContext(ctx).Scan(&res); err != nil {
+		return 0, fmt.Errorf("%s: %s", op, err.Error())
+	}
+
+	return res.Int64, nil
+}
+
+// Apps returns all registered apps
+func (s *Storage) Apps(ctx context.Context) ([]models.App, error) {
+	const op = "storage.postgresql.Apps"
+
+	stmt, err := s.db.Prepare(fmt.Sprintf("SELECT id, name, secret, revoked_before FROM %s", appsTable))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", op, err.Error())
+	}
+
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", op, err.Error())
+	}
+	defer rows.Close()
+
+	var apps []models.App
+	for rows.Next() {
+		var app models.App
+		if err := rows.Scan(&app.Id, &app.Name, &app.Secret, &app.RevokedBefore); err != nil {
+			return nil, fmt.Errorf("%s: %s", op, err.Error())
+		}
+		apps = append(apps, app)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %s", op, err.Error())
+	}
+
+	return apps, nil
+}
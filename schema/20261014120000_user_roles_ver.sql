@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN IF NOT EXISTS roles_ver BIGINT NOT NULL DEFAULT 0;
+
+-- роли меняются и прямым UPDATE, поэтому версию поднимает триггер, а не только SetAdmin
+CREATE OR REPLACE FUNCTION bump_roles_ver() RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.is_admin IS DISTINCT FROM OLD.is_admin AND NEW.roles_ver = OLD.roles_ver THEN
+        NEW.roles_ver := OLD.roles_ver + 1;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_bump_roles_ver
+    BEFORE UPDATE OF is_admin ON users
+    FOR EACH ROW EXECUTE FUNCTION bump_roles_ver();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS users_bump_roles_ver ON users;
+DROP FUNCTION IF EXISTS bump_roles_ver();
+ALTER TABLE users DROP COLUMN IF EXISTS roles_ver;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS revoked_before TIMESTAMPTZ NOT NULL DEFAULT 'epoch';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE apps DROP COLUMN IF EXISTS revoked_before;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- регистрация сохраняет email с доменом в нижнем регистре, приводим к тому же виду старые записи.
+-- если у двух записей домены отличаются только регистром, миграция упадет на unique:
+-- такие дубликаты надо разобрать вручную
+UPDATE users
+SET email = substring(email from '^(.*)@') || '@' || lower(substring(email from '[^@]*$'))
+WHERE email <> substring(email from '^(.*)@') || '@' || lower(substring(email from '[^@]*$'));
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_normalized
+    ON users ((substring(email from '^(.*)@') || '@' || lower(substring(email from '[^@]*$'))));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_users_email_normalized;
+-- +goose StatementEnd
@@ -0,0 +1,50 @@
+package schema
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var migrations embed.FS
+
+var ErrNoMigrations = errors.New("no migrations found")
+
+// Version returns the latest goose migration version, taken from the file name prefix
+func Version() (int64, error) {
+	return version(migrations)
+}
+
+func version(fsys fs.FS) (int64, error) {
+	const op = "schema.Version"
+
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var latest int64
+	for _, name := range names {
+		prefix, _, found := strings.Cut(name, "_")
+		if !found {
+			return 0, fmt.Errorf("%s: migration without version prefix: %s", op, name)
+		}
+
+		v, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s: invalid migration version %s: %w", op, name, err)
+		}
+
+		latest = max(latest, v)
+	}
+
+	if latest == 0 {
+		return 0, fmt.Errorf("%s: %w", op, ErrNoMigrations)
+	}
+
+	return latest, nil
+}
@@ -0,0 +1,46 @@
+package schema
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersion_Embedded(t *testing.T) {
+	v, err := Version()
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, v, int64(20261014130000))
+}
+
+func TestVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		want    int64
+		wantErr bool
+	}{
+		{name: "latest wins", files: fstest.MapFS{
+			"20241008144213_user_table.sql": {},
+			"20261014120000_roles.sql":      {},
+			"20250101000000_other.sql":      {},
+			"README.md":                     {},
+		}, want: 20261014120000},
+		{name: "empty", files: fstest.MapFS{}, wantErr: true},
+		{name: "without prefix", files: fstest.MapFS{"init.sql": {}}, wantErr: true},
+		{name: "bad prefix", files: fstest.MapFS{"v1_init.sql": {}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := version(tt.files)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
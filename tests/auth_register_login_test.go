@@ -11,6 +11,8 @@ import (
 	ssov1 "github.com/maximka200/buffpr/gen/go/sso"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
@@ -73,6 +75,19 @@ func TestRegisterLogin_DuplicateRegistration(t *testing.T) {
 	assert.ErrorContains(t, err, fmt.Sprintf("User already exist with email: %s", email))
 }
 
+func TestRegister_InvalidEmail(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	password := gofakeit.Password(true, true, true, true, false, passDefLen)
+
+	for _, email := range []string{"plainaddress", "a b@example.com", "a@b@example.com"} {
+		respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+		require.Error(t, err)
+		assert.Empty(t, respReg.GetUserId())
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+}
+
 func TestLogin_InvalidCredentials(t *testing.T) {
 	ctx, st := suite.NewSuite(t)
 